@@ -0,0 +1,194 @@
+package e2e
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/richburroughs/cluster-api-provider-vcluster/test/e2e/helpers"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterPausedAnnotation mirrors cluster.x-k8s.io/paused, the annotation
+// CAPI controllers (including this provider) check before reconciling an
+// owned infrastructure resource.
+const clusterPausedAnnotation = "cluster.x-k8s.io/paused"
+
+var _ = ginkgo.Describe("capi lifecycle", func() {
+	ginkgo.Context("management cluster", func() {
+
+		var (
+			mgmtClient  client.Client
+			ctx         context.Context
+			namespace   string
+			clusterKey  client.ObjectKey
+			vclusterKey client.ObjectKey
+		)
+
+		ginkgo.BeforeEach(func() {
+			ctx = context.TODO()
+
+			scheme := runtime.NewScheme()
+			gomega.Expect(clusterv1.AddToScheme(scheme)).To(gomega.Succeed())
+
+			var err error
+			mgmtClient, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			namespace = os.Getenv("NAMESPACE")
+			name := os.Getenv("CLUSTER_NAME")
+			clusterKey = client.ObjectKey{Name: name, Namespace: namespace}
+			vclusterKey = client.ObjectKey{Name: name, Namespace: namespace}
+		})
+
+		ginkgo.It("stops reconciling once the Cluster is paused", func() {
+			const pausedVersion = "0.20.1"
+
+			cluster := &clusterv1.Cluster{}
+			gomega.Expect(mgmtClient.Get(ctx, clusterKey, cluster)).To(gomega.Succeed())
+
+			before := getVCluster(ctx, mgmtClient, vclusterKey)
+			beforeVersion := nestedString(before, "status", "version")
+
+			if cluster.Annotations == nil {
+				cluster.Annotations = map[string]string{}
+			}
+			cluster.Annotations[clusterPausedAnnotation] = "true"
+			gomega.Expect(mgmtClient.Update(ctx, cluster)).To(gomega.Succeed())
+
+			ginkgo.DeferCleanup(func() {
+				gomega.Expect(mgmtClient.Get(ctx, clusterKey, cluster)).To(gomega.Succeed())
+				delete(cluster.Annotations, clusterPausedAnnotation)
+				gomega.Expect(mgmtClient.Update(ctx, cluster)).To(gomega.Succeed())
+			})
+
+			// Bump the chart version while paused: the same mutation the
+			// in-place upgrade spec uses to prove the provider *does*
+			// reconcile. Here it shouldn't take effect at all, which is the
+			// only way this test can distinguish "pause honored" from
+			// "nothing happened to reconcile anyway".
+			vcluster := getVCluster(ctx, mgmtClient, vclusterKey)
+			gomega.Expect(unstructured.SetNestedField(vcluster.Object, pausedVersion, "spec", "helmRelease", "chart", "version")).To(gomega.Succeed())
+			gomega.Expect(mgmtClient.Update(ctx, vcluster)).To(gomega.Succeed())
+
+			gomega.Consistently(func(g gomega.Gomega) {
+				after := getVCluster(ctx, mgmtClient, vclusterKey)
+				g.Expect(nestedString(after, "status", "version")).To(gomega.Equal(beforeVersion), "status.version changed while Cluster was paused")
+			}).WithContext(ctx).WithTimeout(30 * time.Second).WithPolling(5 * time.Second).Should(gomega.Succeed())
+
+			gomega.Expect(mgmtClient.Get(ctx, clusterKey, cluster)).To(gomega.Succeed())
+			delete(cluster.Annotations, clusterPausedAnnotation)
+			gomega.Expect(mgmtClient.Update(ctx, cluster)).To(gomega.Succeed())
+
+			// Unpaused, the provider should pick the pending version back up.
+			updated := helpers.GetK8sObjectWithRetry(ctx, func(ctx context.Context, name string, _ metav1.GetOptions) (*unstructured.Unstructured, error) {
+				u := newVClusterObject()
+				err := mgmtClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, u)
+				return u, err
+			}, vclusterKey.Name,
+				helpers.WithTimeout(10*time.Minute),
+				helpers.Until(func(u *unstructured.Unstructured) bool {
+					return isConditionTrue(u, "Available") && nestedString(u, "status", "version") == pausedVersion
+				}),
+			)
+			gomega.Expect(nestedString(updated, "status", "version")).To(gomega.Equal(pausedVersion))
+		})
+
+		ginkgo.It("performs an in-place upgrade when the helm chart version changes", func() {
+			const targetVersion = "0.20.0"
+
+			vcluster := getVCluster(ctx, mgmtClient, vclusterKey)
+			gomega.Expect(unstructured.SetNestedField(vcluster.Object, targetVersion, "spec", "helmRelease", "chart", "version")).To(gomega.Succeed())
+			gomega.Expect(mgmtClient.Update(ctx, vcluster)).To(gomega.Succeed())
+
+			updated := helpers.GetK8sObjectWithRetry(ctx, func(ctx context.Context, name string, _ metav1.GetOptions) (*unstructured.Unstructured, error) {
+				u := newVClusterObject()
+				err := mgmtClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, u)
+				return u, err
+			}, vclusterKey.Name,
+				helpers.WithTimeout(10*time.Minute),
+				helpers.Until(func(u *unstructured.Unstructured) bool {
+					return isConditionTrue(u, "Available") && nestedString(u, "status", "version") == targetVersion
+				}),
+			)
+			gomega.Expect(nestedString(updated, "status", "version")).To(gomega.Equal(targetVersion))
+		})
+
+		ginkgo.It("reattaches cleanly when the Cluster owner reference is recreated", func() {
+			cluster := &clusterv1.Cluster{}
+			gomega.Expect(mgmtClient.Get(ctx, clusterKey, cluster)).To(gomega.Succeed())
+
+			vcluster := getVCluster(ctx, mgmtClient, vclusterKey)
+			gomega.Expect(vcluster.GetFinalizers()).NotTo(gomega.BeEmpty(), "VCluster should carry a finalizer while owned by a Cluster")
+
+			gomega.Expect(mgmtClient.Delete(ctx, cluster)).To(gomega.Succeed())
+
+			// Cluster carries finalizers, so Delete only sets
+			// deletionTimestamp; wait for it to actually disappear before
+			// recreating, or Create below would race an AlreadyExists.
+			helpers.GetK8sObjectWithRetry(ctx, func(ctx context.Context, name string, _ metav1.GetOptions) (*clusterv1.Cluster, error) {
+				c := &clusterv1.Cluster{}
+				err := mgmtClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, c)
+				return c, err
+			}, clusterKey.Name,
+				helpers.WithTimeout(2*time.Minute),
+				helpers.IgnoreNotFound(),
+			)
+
+			recreated := cluster.DeepCopy()
+			recreated.ResourceVersion = ""
+			recreated.UID = ""
+			gomega.Expect(mgmtClient.Create(ctx, recreated)).To(gomega.Succeed())
+
+			// The provider should re-adopt the existing VCluster rather than
+			// leaking the old one, and the finalizer should still be present.
+			reattached := helpers.GetK8sObjectWithRetry(ctx, func(ctx context.Context, name string, _ metav1.GetOptions) (*unstructured.Unstructured, error) {
+				u := newVClusterObject()
+				err := mgmtClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, u)
+				return u, err
+			}, vclusterKey.Name,
+				helpers.WithTimeout(2*time.Minute),
+				helpers.Until(func(u *unstructured.Unstructured) bool {
+					return len(u.GetFinalizers()) > 0
+				}),
+			)
+			gomega.Expect(reattached.GetFinalizers()).NotTo(gomega.BeEmpty())
+		})
+	})
+})
+
+func newVClusterObject() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: vclusterGVR.Group, Version: vclusterGVR.Version, Kind: "VCluster"})
+	return u
+}
+
+func getVCluster(ctx context.Context, c client.Client, key client.ObjectKey) *unstructured.Unstructured {
+	u := newVClusterObject()
+	gomega.Expect(c.Get(ctx, key, u)).To(gomega.Succeed())
+	return u
+}
+
+func readyCondition(u *unstructured.Unstructured) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]interface{}); ok && condition["type"] == "Ready" {
+			return condition
+		}
+	}
+	return nil
+}
+
+func nestedString(u *unstructured.Unstructured, fields ...string) string {
+	s, _, _ := unstructured.NestedString(u.Object, fields...)
+	return s
+}
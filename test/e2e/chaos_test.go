@@ -0,0 +1,137 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/richburroughs/cluster-api-provider-vcluster/test/e2e/helpers"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// controlPlaneLabelSelector matches the vcluster syncer/control-plane pod
+// in its host namespace, the same label the helm chart stamps on it.
+const controlPlaneLabelSelector = "app=vcluster"
+
+var _ = ginkgo.Describe("chaos", func() {
+	ginkgo.Context("control-plane disruption", func() {
+
+		var (
+			ctx            context.Context
+			namespace      string
+			name           string
+			hostClient     *kubernetes.Clientset
+			mgmtClient     client.Client
+			vclusterConfig *rest.Config
+			vclusterClient *kubernetes.Clientset
+		)
+
+		ginkgo.BeforeEach(func() {
+			ctx = context.TODO()
+			namespace = os.Getenv("NAMESPACE")
+			name = os.Getenv("CLUSTER_NAME")
+
+			hostConfig := mustGetHostConfig()
+			var err error
+			hostClient, err = kubernetes.NewForConfig(hostConfig)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			mgmtClient, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: runtime.NewScheme()})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			_, vclusterConfig, vclusterClient, err = connectToVCluster(ctx, runtime.NewScheme(), namespace, name)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			_ = vclusterConfig
+		})
+
+		ginkgo.It("recovers from control-plane pod deletion", func() {
+			deploymentName := "chaos-test-deployment"
+			replicas := int32(1)
+			created, err := vclusterClient.AppsV1().Deployments("default").Create(ctx, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "chaos-test"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "chaos-test"}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}},
+						},
+					},
+				},
+			}, metav1.CreateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			originalUID := created.UID
+
+			deleteControlPlanePod(ctx, hostClient, namespace)
+
+			// The workload client reconnects through the existing
+			// port-forward once the control-plane pod is back; the
+			// Deployment it created before the disruption must still be
+			// there with the same UID.
+			recovered := helpers.GetK8sObjectWithRetry(ctx, vclusterClient.AppsV1().Deployments("default").Get, deploymentName,
+				helpers.WithTimeout(5*time.Minute),
+			)
+			gomega.Expect(recovered.UID).To(gomega.Equal(originalUID), "Deployment should survive control-plane pod deletion with the same UID")
+
+			// The vcluster API accepts new objects again once the control
+			// plane is back up. Only creation inside the vcluster is checked;
+			// whether new objects reach the host is not.
+			helpers.CreateK8sObjectWithRetry(ctx, vclusterClient.CoreV1().Namespaces().Create, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "chaos-test-post-recovery"},
+			}, helpers.WithTimeout(2*time.Minute))
+		})
+
+		ginkgo.It("surfaces a degraded Ready=False condition when the backing PVC is deleted", func() {
+			deleteControlPlanePVC(ctx, hostClient, namespace, name)
+
+			degraded := helpers.GetK8sObjectWithRetry(ctx, func(ctx context.Context, objName string, _ metav1.GetOptions) (*unstructured.Unstructured, error) {
+				u := newVClusterObject()
+				err := mgmtClient.Get(ctx, client.ObjectKey{Name: objName, Namespace: namespace}, u)
+				return u, err
+			}, name,
+				helpers.WithTimeout(5*time.Minute),
+				helpers.Until(func(u *unstructured.Unstructured) bool {
+					condition := readyCondition(u)
+					return condition != nil && condition["status"] == "False"
+				}),
+			)
+
+			condition := readyCondition(degraded)
+			reason, _ := condition["reason"].(string)
+			gomega.Expect(reason).NotTo(gomega.BeEmpty(), "expected a non-empty reason on the degraded Ready condition")
+		})
+	})
+})
+
+// deleteControlPlanePod deletes the vcluster's syncer/control-plane pod(s)
+// in its host namespace so the suite can assert the provider/workload
+// client recover once it's rescheduled.
+func deleteControlPlanePod(ctx context.Context, hostClient *kubernetes.Clientset, namespace string) {
+	pods, err := hostClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: controlPlaneLabelSelector})
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	gomega.Expect(pods.Items).NotTo(gomega.BeEmpty(), "expected to find a control-plane pod to delete")
+
+	for _, pod := range pods.Items {
+		helpers.DeleteK8sObjectWithRetry(ctx, hostClient.CoreV1().Pods(namespace).Delete, pod.Name)
+	}
+}
+
+// deleteControlPlanePVC deletes the PVC backing the vcluster's embedded
+// etcd/sqlite store, simulating storage loss.
+func deleteControlPlanePVC(ctx context.Context, hostClient *kubernetes.Clientset, namespace, name string) {
+	pvcName := fmt.Sprintf("data-%s-0", name)
+	helpers.DeleteK8sObjectWithRetry(ctx, hostClient.CoreV1().PersistentVolumeClaims(namespace).Delete, pvcName)
+}
@@ -0,0 +1,90 @@
+package e2e
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+)
+
+// conformanceFlag gates the (slow) upstream conformance run behind an
+// explicit opt-in, the same way -distro/-storage gate the topology matrix.
+var conformanceFlag = flag.Bool("conformance", false, "run the upstream Kubernetes conformance suite (via sonobuoy) against each connected vcluster; slow, opt-in")
+
+// runConformance shells out to sonobuoy against the vcluster reachable via
+// kubeconfigPath, waits for the run to finish, and fails the spec with the
+// failing test names if the certified-conformance plugin reports any.
+//
+// We shell out rather than vendoring k8s.io/kubernetes/test/e2e as a
+// library: that package drags in the full e2e framework and its own flag
+// set, which would fight with the matrix flags defined in e2e_test.go.
+// sonobuoy already packages the conformance image and a stable results
+// format, so it's a much smaller surface to parse.
+func runConformance(ctx context.Context, kubeconfigPath string) {
+	ctx, cancel := context.WithTimeout(ctx, conformanceTimeout)
+	defer cancel()
+
+	runCmd := exec.CommandContext(ctx, "sonobuoy", "run",
+		"--kubeconfig", kubeconfigPath,
+		"--mode=certified-conformance",
+		"--wait",
+	)
+	output, err := runCmd.CombinedOutput()
+	gomega.Expect(err).NotTo(gomega.HaveOccurred(), "sonobuoy run failed: %s", output)
+
+	retrieveCmd := exec.CommandContext(ctx, "sonobuoy", "retrieve", "--kubeconfig", kubeconfigPath)
+	archive, err := retrieveCmd.Output()
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	archivePath := strings.TrimSpace(string(archive))
+
+	resultsCmd := exec.CommandContext(ctx, "sonobuoy", "results", archivePath, "--kubeconfig", kubeconfigPath, "--plugin", "e2e", "--mode", "detailed")
+	results, err := resultsCmd.Output()
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+	failures := failedConformanceTests(results)
+	for _, name := range failures {
+		ginkgo.AddReportEntry("conformance failure", name)
+	}
+	gomega.Expect(failures).To(gomega.BeEmpty(), "%d conformance test(s) failed:\n%s", len(failures), strings.Join(failures, "\n"))
+}
+
+// failedConformanceTests scans `sonobuoy results --mode detailed` output,
+// which is one JSON-lines-ish record per test prefixed with its status, for
+// failed entries and returns their names.
+func failedConformanceTests(detailed []byte) []string {
+	var failed []string
+	scanner := bufio.NewScanner(strings.NewReader(string(detailed)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, `"status":"failed"`) {
+			if name := extractJSONField(line, "name"); name != "" {
+				failed = append(failed, name)
+			}
+		}
+	}
+	return failed
+}
+
+func extractJSONField(line, field string) string {
+	marker := fmt.Sprintf(`"%s":"`, field)
+	idx := strings.Index(line, marker)
+	if idx == -1 {
+		return ""
+	}
+	rest := line[idx+len(marker):]
+	end := strings.Index(rest, `"`)
+	if end == -1 {
+		return ""
+	}
+	return rest[:end]
+}
+
+// conformanceTimeout bounds how long TestRunE2ETests waits on a single
+// conformance run; certified-conformance typically takes 30-60 minutes.
+const conformanceTimeout = time.Hour
@@ -1,10 +1,15 @@
 package e2e
 
 import (
+	"bytes"
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/loft-sh/log"
@@ -14,184 +19,391 @@ import (
 	logutil "github.com/loft-sh/vcluster/pkg/util/log"
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	"github.com/richburroughs/cluster-api-provider-vcluster/test/e2e/helpers"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// Test matrix flags. Distro and storage accept comma-separated lists so CI
+// can request several topologies in one run; replica counts apply to every
+// combination in the matrix.
+var (
+	distroFlag               = flag.String("distro", "k3s", "comma-separated list of vcluster distributions to exercise (k3s,k0s,k8s,eks)")
+	storageFlag              = flag.String("storage", "embedded", "comma-separated list of backing stores to exercise (embedded,external-etcd,sqlite)")
+	controlPlaneReplicasFlag = flag.Int("controlPlaneReplicas", 1, "number of vcluster control-plane replicas requested for every topology in the matrix")
+	etcdReplicasFlag         = flag.Int("etcdReplicas", 1, "number of etcd replicas requested for every topology using external-etcd storage")
+)
+
+// topology is one entry in the e2e test matrix: a distro paired with a
+// storage backend. Replica counts are shared across the whole matrix since
+// they don't meaningfully interact with distro/storage choice.
+type topology struct {
+	distro  string
+	storage string
+}
+
+func (t topology) String() string {
+	return fmt.Sprintf("%s-%s", t.distro, t.storage)
+}
+
+// name returns a DNS-1123-safe identifier for resources created for this
+// topology, e.g. "vcluster-k3s-embedded".
+func (t topology) name() string {
+	return fmt.Sprintf("vcluster-%s-%s", t.distro, t.storage)
+}
+
+func testMatrix() []topology {
+	var matrix []topology
+	for _, distro := range splitAndTrim(*distroFlag) {
+		for _, storage := range splitAndTrim(*storageFlag) {
+			matrix = append(matrix, topology{distro: distro, storage: storage})
+		}
+	}
+	return matrix
+}
+
+func splitAndTrim(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+var vclusterCRTemplate = template.Must(template.New("vcluster").Parse(`
+apiVersion: infrastructure.cluster.x-k8s.io/v1alpha1
+kind: VCluster
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  controlPlaneEndpoint: {}
+  helmRelease:
+    chart:
+      name: vcluster
+  values: |
+    controlPlane:
+      distro:
+        {{ .Distro }}:
+          enabled: true
+      statefulSet:
+        highAvailability:
+          replicas: {{ .ControlPlaneReplicas }}
+      backingStore:
+        {{ .Storage }}:
+          enabled: true
+{{- if .EtcdReplicas }}
+          etcd:
+            replicas: {{ .EtcdReplicas }}
+{{- end }}
+`))
+
+var vclusterGVR = schema.GroupVersionResource{
+	Group:    "infrastructure.cluster.x-k8s.io",
+	Version:  "v1alpha1",
+	Resource: "vclusters",
+}
+
+// renderVClusterCR templates a VCluster CR for the given topology and
+// returns it as an unstructured object ready to be applied to the
+// management cluster.
+func renderVClusterCR(top topology, namespace string) (*unstructured.Unstructured, error) {
+	var buf bytes.Buffer
+	err := vclusterCRTemplate.Execute(&buf, struct {
+		Name                 string
+		Namespace            string
+		Distro               string
+		Storage              string
+		ControlPlaneReplicas int
+		EtcdReplicas         int
+	}{
+		Name:                 top.name(),
+		Namespace:            namespace,
+		Distro:               top.distro,
+		Storage:              top.storage,
+		ControlPlaneReplicas: *controlPlaneReplicasFlag,
+		EtcdReplicas:         etcdReplicasForStorage(top.storage),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal(buf.Bytes(), &obj); err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+func etcdReplicasForStorage(storage string) int {
+	if storage != "external-etcd" {
+		return 0
+	}
+	return *etcdReplicasFlag
+}
+
+// waitForVClusterReady polls the management cluster until the VCluster CR's
+// Ready condition is true, or the timeout elapses.
+func waitForVClusterReady(ctx context.Context, mgmtClient client.Client, name, namespace string) error {
+	return wait.PollUntilContextTimeout(ctx, 5*time.Second, 10*time.Minute, true, func(ctx context.Context) (bool, error) {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(schema.GroupVersionKind{Group: vclusterGVR.Group, Version: vclusterGVR.Version, Kind: "VCluster"})
+		err := mgmtClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, u)
+		if err != nil {
+			return false, nil
+		}
+		return isConditionTrue(u, "Ready"), nil
+	})
+}
+
+func isConditionTrue(u *unstructured.Unstructured, conditionType string) bool {
+	conditions, found, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil || !found {
+		return false
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == conditionType {
+			return condition["status"] == "True"
+		}
+	}
+	return false
+}
+
+// connectToVCluster runs the vclusterctl connect-helm flow to port-forward
+// into a running vcluster and returns a ready-to-use client once the port
+// forward is up. It's shared by the matrix specs in this file, which connect
+// to each topology's own vcluster, and by sync_test.go and chaos_test.go,
+// which connect on their own to the CLUSTER_NAME vcluster to compare
+// host-side and vcluster-side state.
+func connectToVCluster(ctx context.Context, scheme *runtime.Scheme, namespace, name string) (*os.File, *rest.Config, *kubernetes.Clientset, error) {
+	l := log.GetInstance()
+
+	vKubeconfigFile, err := os.CreateTemp(os.TempDir(), "vcluster_e2e_kubeconfig_")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	localPort, err := strconv.Atoi(os.Getenv("LOCAL_PORT"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	connectCmd := cmd.ConnectCmd{
+		Log: l,
+		GlobalFlags: &flags.GlobalFlags{
+			Namespace: namespace,
+			Debug:     true,
+		},
+		ConnectOptions: cli.ConnectOptions{
+			UpdateCurrent:   false,
+			KubeConfig:      vKubeconfigFile.Name(),
+			LocalPort:       localPort, // choosing a port that usually should be unused
+			BackgroundProxy: true,
+		},
+	}
+	if err := cli.ConnectHelm(ctx, &connectCmd.ConnectOptions, connectCmd.GlobalFlags, name, nil, connectCmd.Log); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var vclusterConfig *rest.Config
+	var vclusterClient *kubernetes.Clientset
+	err = wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, false, func(ctx context.Context) (bool, error) {
+		output, err := os.ReadFile(vKubeconfigFile.Name())
+		if err != nil {
+			return false, nil
+		}
+
+		// try to parse config from file with retry because the file content might not be written
+		vclusterConfig, err = clientcmd.RESTConfigFromKubeConfig(output)
+		if err != nil {
+			return false, err
+		}
+		vclusterConfig.Timeout = time.Minute
+
+		// create kubernetes client using the config retry in case port forwarding is not ready yet
+		vclusterClient, err = kubernetes.NewForConfig(vclusterConfig)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = client.New(vclusterConfig, client.Options{Scheme: scheme})
+		return err == nil, nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// port forwarding may still not be ready the moment the client can be
+	// constructed, so retry the first real call through it
+	helpers.GetK8sObjectWithRetry(ctx, vclusterClient.CoreV1().ServiceAccounts("default").Get, "default", helpers.WithTimeout(time.Minute))
+
+	return vKubeconfigFile, vclusterConfig, vclusterClient, nil
+}
+
 func TestRunE2ETests(t *testing.T) {
+	flag.Parse()
 	gomega.RegisterFailHandler(ginkgo.Fail)
+	registerMatrixSpecs()
 	ginkgo.RunSpecs(t, "e2e suite")
 }
 
-var _ = ginkgo.Describe("e2e test", func() {
-	ginkgo.Context("E2E", func() {
-
-		var (
-			vclusterConfig  *rest.Config
-			vclusterClient  *kubernetes.Clientset
-			vKubeconfigFile *os.File
-			ctx             context.Context
-		)
-
-		ginkgo.BeforeEach(func() {
-			ctx = context.TODO()
-			ctrl.SetLogger(logutil.NewLog(0))
-			l := log.GetInstance()
-			scheme := runtime.NewScheme()
-
-			// run port forwarder and retrieve kubeconfig for the vcluster
-			var err error
-			vKubeconfigFile, err = os.CreateTemp(os.TempDir(), "vcluster_e2e_kubeconfig_")
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-			namespace := os.Getenv("NAMESPACE")
-			name := os.Getenv("CLUSTER_NAME")
-			localPort, err := strconv.Atoi(os.Getenv("LOCAL_PORT"))
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-			connectCmd := cmd.ConnectCmd{
-				Log: l,
-				GlobalFlags: &flags.GlobalFlags{
-					Namespace: namespace,
-					Debug:     true,
-				},
-				ConnectOptions: cli.ConnectOptions{
-					UpdateCurrent:   false,
-					KubeConfig:      vKubeconfigFile.Name(),
-					LocalPort:       localPort, // choosing a port that usually should be unused
-					BackgroundProxy: true,
-				},
-			}
-			err = cli.ConnectHelm(ctx, &connectCmd.ConnectOptions, connectCmd.GlobalFlags, name, nil, connectCmd.Log)
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-			err = wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, false, func(ctx context.Context) (bool, error) {
-				output, err := os.ReadFile(vKubeconfigFile.Name())
-				if err != nil {
-					return false, nil
-				}
-
-				// try to parse config from file with retry because the file content might not be written
-				vclusterConfig, err = clientcmd.RESTConfigFromKubeConfig(output)
-				if err != nil {
-					return false, err
-				}
-				vclusterConfig.Timeout = time.Minute
-
-				// create kubernetes client using the config retry in case port forwarding is not ready yet
-				vclusterClient, err = kubernetes.NewForConfig(vclusterConfig)
-				if err != nil {
-					return false, err
-				}
-
-				_, err = client.New(vclusterConfig, client.Options{Scheme: scheme})
-				if err != nil {
-					return false, err
-				}
-
-				// try to use the client with retry in case port forwarding is not ready yet
-				_, err = vclusterClient.CoreV1().ServiceAccounts("default").Get(ctx, "default", metav1.GetOptions{})
-				if err != nil {
-					return false, err
-				}
-				return true, nil
-			})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		})
+// registerMatrixSpecs builds one "e2e test" Describe block per topology in
+// the matrix so that each distro/storage/replica combination runs the same
+// Deploy/Scale/Delete specs against its own vcluster. Flags must be parsed
+// (see TestRunE2ETests) before this runs, since the matrix is derived from
+// them.
+func registerMatrixSpecs() {
+	for _, top := range testMatrix() {
+		top := top
+		ginkgo.Describe(fmt.Sprintf("e2e test [%s]", top), func() {
+			ginkgo.Context("E2E", ginkgo.Ordered, func() {
 
-		ginkgo.It("Deploys Workload to VirtualCluster successfully", func() {
-			ctx = context.TODO()
-			replicas := int32(2)
-			deploymentName := "example-deployment"
-			namespace := "default"
-			deployment := &appsv1.Deployment{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      deploymentName,
-					Namespace: namespace,
-				},
-				Spec: appsv1.DeploymentSpec{
-					Replicas: &replicas,
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							"app": "example",
-						},
-					},
-					Template: corev1.PodTemplateSpec{
+				var (
+					mgmtClient      client.Client
+					vclusterConfig  *rest.Config
+					vclusterClient  *kubernetes.Clientset
+					vKubeconfigFile *os.File
+					ctx             context.Context
+					namespace       string
+				)
+
+				ginkgo.BeforeEach(func() {
+					ctx = context.TODO()
+					ctrl.SetLogger(logutil.NewLog(0))
+					scheme := runtime.NewScheme()
+
+					namespace = os.Getenv("NAMESPACE")
+
+					var err error
+					mgmtClient, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+					vclusterCR, err := renderVClusterCR(top, namespace)
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+					err = mgmtClient.Patch(ctx, vclusterCR, client.Apply, client.ForceOwnership, client.FieldOwner("e2e-test-matrix"))
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+					err = waitForVClusterReady(ctx, mgmtClient, top.name(), namespace)
+					gomega.Expect(err).NotTo(gomega.HaveOccurred(), "VCluster %s never became Ready", top.name())
+
+					// Connect to this topology's own VCluster, not a fixed
+					// CLUSTER_NAME from the environment, so each matrix
+					// combination actually exercises the Deploy/Scale/Delete
+					// specs against the cluster it just provisioned.
+					vKubeconfigFile, vclusterConfig, vclusterClient, err = connectToVCluster(ctx, scheme, namespace, top.name())
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				})
+
+				ginkgo.AfterAll(func() {
+					vclusterCR := newVClusterObject()
+					vclusterCR.SetName(top.name())
+					vclusterCR.SetNamespace(namespace)
+					helpers.DeleteK8sObjectWithRetry(ctx, func(ctx context.Context, _ string, _ metav1.DeleteOptions) error {
+						return mgmtClient.Delete(ctx, vclusterCR)
+					}, top.name())
+				})
+
+				ginkgo.It("Deploys Workload to VirtualCluster successfully", func() {
+					ctx = context.TODO()
+					replicas := int32(2)
+					deploymentName := "example-deployment"
+					namespace := "default"
+					deployment := &appsv1.Deployment{
 						ObjectMeta: metav1.ObjectMeta{
-							Labels: map[string]string{
-								"app": "example",
-							},
+							Name:      deploymentName,
+							Namespace: namespace,
 						},
-						Spec: corev1.PodSpec{
-							Containers: []corev1.Container{
-								{
-									Name:  "nginx",
-									Image: "nginx",
+						Spec: appsv1.DeploymentSpec{
+							Replicas: &replicas,
+							Selector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "example",
+								},
+							},
+							Template: corev1.PodTemplateSpec{
+								ObjectMeta: metav1.ObjectMeta{
+									Labels: map[string]string{
+										"app": "example",
+									},
+								},
+								Spec: corev1.PodSpec{
+									Containers: []corev1.Container{
+										{
+											Name:  "nginx",
+											Image: "nginx",
+										},
+									},
 								},
 							},
 						},
-					},
-				},
-			}
-
-			_, err := vclusterClient.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-			deployment, err = vclusterClient.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-			// Wait for the pods of the deployment to be running
-			err = wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, false, func(ctx context.Context) (bool, error) {
-				// Update the deployment status
-				updatedDeployment, err := vclusterClient.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
-				if err != nil {
-					return false, err
-				}
-
-				if updatedDeployment.Status.ReadyReplicas == *deployment.Spec.Replicas {
-					// All replicas are ready
-					return true, nil
-				}
-
-				return false, nil
-			})
+					}
 
-			gomega.Expect(err).NotTo(gomega.HaveOccurred(), "Timeout reached waiting for deployment pods to be running")
-		})
+					_, err := vclusterClient.AppsV1().Deployments("default").Create(ctx, deployment, metav1.CreateOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
-		ginkgo.It("Scale Deployment successfully", func() {
-			deployment, err := vclusterClient.AppsV1().Deployments("default").Get(ctx, "example-deployment", metav1.GetOptions{})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+					deployment, err = vclusterClient.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
-			replicas := int32(5)
-			deployment.Spec.Replicas = &replicas
-			_, err = vclusterClient.AppsV1().Deployments("default").Update(ctx, deployment, metav1.UpdateOptions{})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		})
+					// Wait for the pods of the deployment to be running
+					helpers.GetK8sObjectWithRetry(ctx, vclusterClient.AppsV1().Deployments(namespace).Get, deploymentName,
+						helpers.WithTimeout(time.Minute),
+						helpers.Until(func(d *appsv1.Deployment) bool {
+							return d.Status.ReadyReplicas == *deployment.Spec.Replicas
+						}),
+					)
+				})
 
-		ginkgo.It("Delete VirtualCluster successfully", func() {
-			_, err := vclusterClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "vcluster-example",
-				},
-			}, metav1.CreateOptions{})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-			// Delete the VirtualCluster
-			err = vclusterClient.CoreV1().Namespaces().Delete(ctx, "vcluster-example", metav1.DeleteOptions{})
-			gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		})
+				ginkgo.It("Scale Deployment successfully", func() {
+					deployment, err := vclusterClient.AppsV1().Deployments("default").Get(ctx, "example-deployment", metav1.GetOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
-		ginkgo.AfterEach(func() {
-			defer os.Remove(vKubeconfigFile.Name())
-		})
-	})
+					replicas := int32(5)
+					deployment.Spec.Replicas = &replicas
+					_, err = vclusterClient.AppsV1().Deployments("default").Update(ctx, deployment, metav1.UpdateOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+				})
 
-})
+				ginkgo.It("Delete VirtualCluster successfully", func() {
+					_, err := vclusterClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "vcluster-example",
+						},
+					}, metav1.CreateOptions{})
+					gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+					// Delete the VirtualCluster
+					helpers.DeleteK8sObjectWithRetry(ctx, vclusterClient.CoreV1().Namespaces().Delete, "vcluster-example")
+				})
+
+				ginkgo.It("passes Kubernetes conformance", func() {
+					if !*conformanceFlag {
+						ginkgo.Skip("conformance run not requested (pass -conformance to enable)")
+					}
+					runConformance(ctx, vKubeconfigFile.Name())
+				})
+
+				ginkgo.AfterEach(func() {
+					defer os.Remove(vKubeconfigFile.Name())
+				})
+			})
+		})
+	}
+}
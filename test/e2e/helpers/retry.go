@@ -0,0 +1,145 @@
+// Package helpers provides small generic wrappers around gomega.Eventually
+// for the Kubernetes Get/Create/Delete calls e2e specs poll on repeatedly.
+// Centralizing the retry/backoff behavior here means individual specs don't
+// each hand-roll a wait.PollUntilContextTimeout loop with slightly different
+// timeouts and error handling.
+package helpers
+
+import (
+	"context"
+	"time"
+
+	"github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultTimeout and PollingInterval are used by every helper in this
+// package unless the caller overrides them with the WithTimeout/WithPolling
+// options. They're package vars, not consts, so a suite can tune them once
+// in a TestMain/BeforeSuite for slower environments.
+var (
+	DefaultTimeout  = 2 * time.Minute
+	PollingInterval = time.Second
+)
+
+// K8sGetFunc matches the shape of generated clientset Get methods, e.g.
+// clientset.AppsV1().Deployments(ns).Get.
+type K8sGetFunc[T any] func(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+
+// K8sCreateFunc matches the shape of generated clientset Create methods.
+type K8sCreateFunc[T any] func(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+
+// K8sDeleteFunc matches the shape of generated clientset Delete methods.
+type K8sDeleteFunc func(ctx context.Context, name string, opts metav1.DeleteOptions) error
+
+// Option configures a single retry call.
+type Option func(*options)
+
+type options struct {
+	timeout  time.Duration
+	polling  time.Duration
+	notFound notFoundBehavior
+	until    func(any) bool
+}
+
+type notFoundBehavior int
+
+const (
+	notFoundIsTerminal notFoundBehavior = iota
+	notFoundIsSuccess
+)
+
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+func WithPolling(d time.Duration) Option {
+	return func(o *options) { o.polling = d }
+}
+
+// IgnoreNotFound flips GetK8sObjectWithRetry's NotFound handling: instead
+// of stopping the retry as a terminal failure, a NotFound response
+// immediately satisfies the wait, with the zero value of T returned, and an
+// object that is still found keeps the retry polling. Use this for "wait
+// until gone" loops.
+func IgnoreNotFound() Option {
+	return func(o *options) { o.notFound = notFoundIsSuccess }
+}
+
+// Until adds a readiness predicate to GetK8sObjectWithRetry: the retry keeps
+// polling until the fetched object both returns with no error and satisfies
+// cond, e.g. waiting for a Deployment's ReadyReplicas to catch up with the
+// desired replica count.
+func Until[T any](cond func(T) bool) Option {
+	return func(o *options) {
+		o.until = func(v any) bool { return cond(v.(T)) }
+	}
+}
+
+func resolveOptions(opts []Option) options {
+	o := options{timeout: DefaultTimeout, polling: PollingInterval, notFound: notFoundIsTerminal}
+	for _, apply := range opts {
+		apply(&o)
+	}
+	return o
+}
+
+// GetK8sObjectWithRetry retries get until it succeeds, the timeout elapses,
+// or (by default) a NotFound error is returned, which is treated as
+// terminal. Pass IgnoreNotFound() to instead treat NotFound itself as the
+// awaited success, for "wait until gone" loops; the returned value is the
+// zero value of T in that case, since there's no object left to return.
+func GetK8sObjectWithRetry[T any](ctx context.Context, get K8sGetFunc[T], name string, opts ...Option) T {
+	o := resolveOptions(opts)
+	var result T
+	gomega.Eventually(func(g gomega.Gomega, ctx context.Context) {
+		obj, err := get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if o.notFound == notFoundIsSuccess {
+				var zero T
+				result = zero
+				return
+			}
+			gomega.StopTrying("object not found").Now()
+		}
+		if o.notFound == notFoundIsSuccess {
+			g.Expect(err).To(gomega.HaveOccurred(), "object still present")
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		if o.until != nil {
+			g.Expect(o.until(obj)).To(gomega.BeTrue(), "condition not yet satisfied")
+		}
+		result = obj
+	}).WithContext(ctx).WithTimeout(o.timeout).WithPolling(o.polling).Should(gomega.Succeed())
+	return result
+}
+
+// CreateK8sObjectWithRetry retries create until it succeeds or the timeout
+// elapses. This is useful right after a dependent resource (e.g. a
+// namespace, a CRD) was created and the apiserver hasn't caught up yet.
+func CreateK8sObjectWithRetry[T any](ctx context.Context, create K8sCreateFunc[T], obj T, opts ...Option) T {
+	o := resolveOptions(opts)
+	var result T
+	gomega.Eventually(func(g gomega.Gomega, ctx context.Context) {
+		created, err := create(ctx, obj, metav1.CreateOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		result = created
+	}).WithContext(ctx).WithTimeout(o.timeout).WithPolling(o.polling).Should(gomega.Succeed())
+	return result
+}
+
+// DeleteK8sObjectWithRetry retries delete until it succeeds. NotFound is
+// treated as success by default, since "the object is gone" is the awaited
+// end-state for a delete; pass a non-default notFound behavior isn't
+// supported here because it would never make sense for Delete.
+func DeleteK8sObjectWithRetry(ctx context.Context, deleteFn K8sDeleteFunc, name string, opts ...Option) {
+	o := resolveOptions(opts)
+	gomega.Eventually(func(g gomega.Gomega, ctx context.Context) {
+		err := deleteFn(ctx, name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}).WithContext(ctx).WithTimeout(o.timeout).WithPolling(o.polling).Should(gomega.Succeed())
+}
@@ -0,0 +1,288 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	"github.com/richburroughs/cluster-api-provider-vcluster/test/e2e/helpers"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ownedByLabel is stamped by the syncer onto every host-side object it
+// mirrors down from the vcluster, regardless of sync mode.
+const ownedByLabel = "vcluster.loft.sh/owned-by"
+
+// widgetCRD is a minimal namespaced CRD used to exercise CRD/CR sync modes
+// without depending on any real-world API.
+var widgetCRD = &apiextensionsv1.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{Name: "widgets.e2e.vcluster.loft.sh"},
+	Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+		Group: "e2e.vcluster.loft.sh",
+		Names: apiextensionsv1.CustomResourceDefinitionNames{
+			Plural:   "widgets",
+			Singular: "widget",
+			Kind:     "Widget",
+			ListKind: "WidgetList",
+		},
+		Scope: apiextensionsv1.NamespaceScoped,
+		Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+			Name:    "v1",
+			Served:  true,
+			Storage: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type:                   "object",
+					XPreserveUnknownFields: boolPtr(true),
+				},
+			},
+			Subresources: &apiextensionsv1.CustomResourceSubresources{
+				Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+			},
+		}},
+	},
+}
+
+// gizmoCRD is the cluster-scoped counterpart to widgetCRD.
+var gizmoCRD = &apiextensionsv1.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{Name: "gizmos.e2e.vcluster.loft.sh"},
+	Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+		Group: "e2e.vcluster.loft.sh",
+		Names: apiextensionsv1.CustomResourceDefinitionNames{
+			Plural:   "gizmos",
+			Singular: "gizmo",
+			Kind:     "Gizmo",
+			ListKind: "GizmoList",
+		},
+		Scope: apiextensionsv1.ClusterScoped,
+		Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+			Name:    "v1",
+			Served:  true,
+			Storage: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type:                   "object",
+					XPreserveUnknownFields: boolPtr(true),
+				},
+			},
+			Subresources: &apiextensionsv1.CustomResourceSubresources{
+				Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+			},
+		}},
+	},
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+var _ = ginkgo.Describe("sync mode", func() {
+	ginkgo.Context("CRD propagation", func() {
+
+		var (
+			ctx          context.Context
+			mgmtClient   client.Client
+			vclusterCRDs *apiextensionsclientset.Clientset
+			vclusterDyn  dynamic.Interface
+			hostDyn      dynamic.Interface
+			hostCRDs     *apiextensionsclientset.Clientset
+			namespace    string
+			name         string
+		)
+
+		ginkgo.BeforeEach(func() {
+			ctx = context.TODO()
+			namespace = os.Getenv("NAMESPACE")
+			name = os.Getenv("CLUSTER_NAME")
+
+			var err error
+			mgmtClient, err = client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: runtime.NewScheme()})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			// Connect independently from the workload suite in e2e_test.go:
+			// these specs compare host-cluster state against vcluster state,
+			// so they need their own client pair rather than reusing one
+			// scoped to a single matrix Context.
+			_, vclusterConfig, _, err := connectToVCluster(ctx, runtime.NewScheme(), namespace, name)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			vclusterCRDs, err = apiextensionsclientset.NewForConfig(vclusterConfig)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			vclusterDyn, err = dynamic.NewForConfig(vclusterConfig)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			hostConfig := mustGetHostConfig()
+			hostCRDs, err = apiextensionsclientset.NewForConfig(hostConfig)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			hostDyn, err = dynamic.NewForConfig(hostConfig)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		})
+
+		ginkgo.It("does not sync the CRD to the host by default, but mirrors plain resources created inside the vcluster", func() {
+			helpers.CreateK8sObjectWithRetry(ctx, vclusterCRDs.ApiextensionsV1().CustomResourceDefinitions().Create, widgetCRD)
+
+			_, err := hostCRDs.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, widgetCRD.Name, metav1.GetOptions{})
+			gomega.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue(), "widgets CRD should not be synced to the host by default")
+
+			// The CRD itself stays vcluster-only, but a plain built-in resource
+			// created inside the vcluster namespace should still be mirrored
+			// host-side, labeled with its owning object: syncing pods doesn't
+			// depend on a CRD or its controller existing.
+			podGVR := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+			pod := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "sprocket", "namespace": namespace},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx"},
+					},
+				},
+			}}
+			_, err = vclusterDyn.Resource(podGVR).Namespace(namespace).Create(ctx, pod, metav1.CreateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			gomega.Eventually(func(g gomega.Gomega) {
+				pods, err := hostDyn.Resource(podGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
+					LabelSelector: fmt.Sprintf("%s=sprocket", ownedByLabel),
+				})
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+				g.Expect(pods.Items).NotTo(gomega.BeEmpty(), "expected the vcluster pod to be mirrored host-side")
+			}).WithContext(ctx).WithTimeout(2 * time.Minute).WithPolling(5 * time.Second).Should(gomega.Succeed())
+		})
+
+		ginkgo.It("syncs the CRD and CR to the host with name translation when sync.toHost.customResources is enabled", func() {
+			gomega.Expect(enableCustomResourceSync(ctx, mgmtClient, namespace, name, gizmoCRD.Spec.Names.Plural+"."+gizmoCRD.Spec.Group)).To(gomega.Succeed())
+
+			_, err := vclusterCRDs.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, gizmoCRD, metav1.CreateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			gizmoGVR := schema.GroupVersionResource{Group: "e2e.vcluster.loft.sh", Version: "v1", Resource: "gizmos"}
+			gizmo := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "e2e.vcluster.loft.sh/v1",
+				"kind":       "Gizmo",
+				"metadata":   map[string]interface{}{"name": "whirligig"},
+				"spec":       map[string]interface{}{"speed": "fast"},
+			}}
+			_, err = vclusterDyn.Resource(gizmoGVR).Create(ctx, gizmo, metav1.CreateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			// Toggling sync.toHost.customResources requires the syncer to
+			// pick up a helm upgrade/restart before it takes effect, the same
+			// as the in-place chart upgrade spec in capi_lifecycle_test.go,
+			// so give it the same 10-minute budget rather than the default.
+			gomega.Eventually(func(g gomega.Gomega) {
+				_, err := hostCRDs.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, gizmoCRD.Name, metav1.GetOptions{})
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}).WithContext(ctx).WithTimeout(10 * time.Minute).WithPolling(5 * time.Second).Should(gomega.Succeed())
+
+			hostName := translatedHostName("whirligig", namespace)
+			var hostGizmo *unstructured.Unstructured
+			gomega.Eventually(func(g gomega.Gomega) {
+				obj, err := hostDyn.Resource(gizmoGVR).Get(ctx, hostName, metav1.GetOptions{})
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+				hostGizmo = obj
+			}).WithContext(ctx).WithTimeout(10 * time.Minute).WithPolling(5 * time.Second).Should(gomega.Succeed())
+
+			// Round-trip status: set it host-side, then confirm it syncs
+			// back down into the vcluster object.
+			gomega.Expect(unstructured.SetNestedField(hostGizmo.Object, "spinning", "status", "phase")).To(gomega.Succeed())
+			_, err = hostDyn.Resource(gizmoGVR).UpdateStatus(ctx, hostGizmo, metav1.UpdateOptions{})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			gomega.Eventually(func(g gomega.Gomega) {
+				obj, err := vclusterDyn.Resource(gizmoGVR).Get(ctx, "whirligig", metav1.GetOptions{})
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+				phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+				g.Expect(phase).To(gomega.Equal("spinning"))
+			}).WithContext(ctx).WithTimeout(2 * time.Minute).WithPolling(5 * time.Second).Should(gomega.Succeed())
+		})
+
+		ginkgo.AfterEach(func() {
+			_ = vclusterCRDs.ApiextensionsV1().CustomResourceDefinitions().Delete(ctx, widgetCRD.Name, metav1.DeleteOptions{})
+			_ = vclusterCRDs.ApiextensionsV1().CustomResourceDefinitions().Delete(ctx, gizmoCRD.Name, metav1.DeleteOptions{})
+		})
+	})
+})
+
+// mustGetHostConfig builds a client config for the management/host cluster
+// the vcluster runs in, parallel to how e2e_test.go builds vclusterConfig
+// for the workload cluster: in-cluster config when run from inside the
+// management cluster, falling back to KUBECONFIG for local runs.
+func mustGetHostConfig() *rest.Config {
+	cfg, err := rest.InClusterConfig()
+	if err == nil {
+		return cfg
+	}
+	cfg, err = restConfigFromKubeconfigEnv()
+	gomega.Expect(err).NotTo(gomega.HaveOccurred(), "could not build a host-cluster client config")
+	return cfg
+}
+
+func restConfigFromKubeconfigEnv() (*rest.Config, error) {
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		kubeconfig = os.ExpandEnv("$HOME/.kube/config")
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// translatedHostName mimics the syncer's default host-side naming scheme:
+// <name>-x-<namespace>-x-<vcluster-name>.
+func translatedHostName(name, namespace string) string {
+	return fmt.Sprintf("%s-x-%s-x-%s", name, namespace, os.Getenv("CLUSTER_NAME"))
+}
+
+// enableCustomResourceSync flips sync.toHost.customResources.<resource>.enabled
+// to true in the VCluster CR's embedded helm values and updates the object.
+// The CR carries those values as a YAML blob (spec.values) rather than a
+// structured field, matching how vclusterCRTemplate in e2e_test.go renders
+// them, so this round-trips through sigs.k8s.io/yaml rather than patching a
+// typed field.
+func enableCustomResourceSync(ctx context.Context, mgmtClient client.Client, namespace, name, resource string) error {
+	vcluster := newVClusterObject()
+	if err := mgmtClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, vcluster); err != nil {
+		return err
+	}
+
+	valuesYAML, _, err := unstructured.NestedString(vcluster.Object, "spec", "values")
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	if valuesYAML != "" {
+		if err := yaml.Unmarshal([]byte(valuesYAML), &values); err != nil {
+			return err
+		}
+	}
+
+	if err := unstructured.SetNestedField(values, true, "sync", "toHost", "customResources", resource, "enabled"); err != nil {
+		return err
+	}
+
+	updatedYAML, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(vcluster.Object, string(updatedYAML), "spec", "values"); err != nil {
+		return err
+	}
+
+	return mgmtClient.Update(ctx, vcluster)
+}